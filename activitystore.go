@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"go.uber.org/zap"
+)
+
+// ActivityStore tracks the last time each user posted in each channel, so
+// checkHistory can find inactive users without depending on the
+// cold-start-unsafe h.chUsers cache or the rate-limited, user-token-only
+// search.messages API.
+type ActivityStore interface {
+	// Touch records that userID posted in channelID at ts.
+	Touch(channelID, userID string, ts time.Time) error
+
+	// FindInactive returns up to limit users in channelID whose last
+	// recorded message was before cutoff, ordered oldest-first. cursor is
+	// nil on the first call and, when more results remain, echoed back as
+	// nextCursor for the next call; a nil nextCursor means there are no
+	// more results.
+	FindInactive(channelID string, cutoff time.Time, limit int64, cursor ActivityCursor) (users []string, nextCursor ActivityCursor, err error)
+}
+
+// ActivityCursor opaquely carries a DynamoDB LastEvaluatedKey between
+// FindInactive calls, so callers resume pagination without reconstructing
+// the GSI and table keys themselves.
+type ActivityCursor map[string]*dynamodb.AttributeValue
+
+const (
+	activityTableChannelKey = "channel_id"
+	activityTableUserKey    = "user_id"
+	activityTableTSAttr     = "last_message_ts"
+	activityGSIName         = "channel-last-message-ts-index"
+)
+
+// dynamoActivityStore is an ActivityStore backed by a DynamoDB table keyed on
+// (channel_id, user_id), with a activityGSIName GSI keyed on (channel_id,
+// last_message_ts) so FindInactive can query for stale users oldest-first
+// without a table scan.
+type dynamoActivityStore struct {
+	db    dynamodbiface.DynamoDBAPI
+	table string
+	log   *zap.Logger
+}
+
+func newDynamoActivityStore(sess *session.Session, table string, log *zap.Logger) *dynamoActivityStore {
+	return &dynamoActivityStore{db: dynamodb.New(sess), table: table, log: log}
+}
+
+func (s *dynamoActivityStore) Touch(channelID, userID string, ts time.Time) error {
+	_, err := s.db.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item: map[string]*dynamodb.AttributeValue{
+			activityTableChannelKey: {S: aws.String(channelID)},
+			activityTableUserKey:    {S: aws.String(userID)},
+			activityTableTSAttr:     {N: aws.String(strconv.FormatInt(ts.Unix(), 10))},
+		},
+	})
+	return err
+}
+
+func (s *dynamoActivityStore) FindInactive(channelID string, cutoff time.Time, limit int64, cursor ActivityCursor) ([]string, ActivityCursor, error) {
+	input := &dynamodb.QueryInput{
+		TableName: aws.String(s.table),
+		IndexName: aws.String(activityGSIName),
+		KeyConditionExpression: aws.String(
+			fmt.Sprintf("%s = :channelID AND %s < :cutoff", activityTableChannelKey, activityTableTSAttr),
+		),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":channelID": {S: aws.String(channelID)},
+			":cutoff":    {N: aws.String(strconv.FormatInt(cutoff.Unix(), 10))},
+		},
+		Limit: aws.Int64(limit),
+	}
+	if len(cursor) > 0 {
+		input.ExclusiveStartKey = cursor
+	}
+
+	out, err := s.db.Query(input)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	users := make([]string, 0, len(out.Items))
+	for _, item := range out.Items {
+		if v := item[activityTableUserKey]; v != nil && v.S != nil {
+			users = append(users, *v.S)
+		}
+	}
+
+	var nextCursor ActivityCursor
+	if len(out.LastEvaluatedKey) > 0 {
+		nextCursor = out.LastEvaluatedKey
+	}
+
+	return users, nextCursor, nil
+}
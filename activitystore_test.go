@@ -0,0 +1,81 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// fakeActivityDB implements dynamodbiface.DynamoDBAPI, serving FindInactive's
+// Query calls from a canned list of pages and recording the
+// ExclusiveStartKey each call was made with.
+type fakeActivityDB struct {
+	dynamodbiface.DynamoDBAPI
+
+	pages       []*dynamodb.QueryOutput
+	startKeys   []map[string]*dynamodb.AttributeValue
+	queryCalled int
+}
+
+func (f *fakeActivityDB) Query(in *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	f.startKeys = append(f.startKeys, in.ExclusiveStartKey)
+	out := f.pages[f.queryCalled]
+	f.queryCalled++
+	return out, nil
+}
+
+func TestFindInactivePaginatesOnFullLastEvaluatedKey(t *testing.T) {
+	lastKey := map[string]*dynamodb.AttributeValue{
+		activityTableChannelKey: {S: aws.String("C1")},
+		activityTableUserKey:    {S: aws.String("U1")},
+		activityTableTSAttr:     {N: aws.String("100")},
+	}
+	fake := &fakeActivityDB{
+		pages: []*dynamodb.QueryOutput{
+			{
+				Items:            []map[string]*dynamodb.AttributeValue{{activityTableUserKey: {S: aws.String("BOT1")}}},
+				LastEvaluatedKey: lastKey,
+			},
+			{
+				Items: []map[string]*dynamodb.AttributeValue{{activityTableUserKey: {S: aws.String("U2")}}},
+			},
+		},
+	}
+	s := &dynamoActivityStore{db: fake, table: "activity"}
+
+	users, next, err := s.FindInactive("C1", time.Unix(200, 0), 25, nil)
+	if err != nil {
+		t.Fatalf("got error %v, want nil", err)
+	}
+	if !reflect.DeepEqual(users, []string{"BOT1"}) {
+		t.Errorf("got users %v, want [BOT1]", users)
+	}
+	if next == nil {
+		t.Fatal("got nil nextCursor, want non-nil")
+	}
+
+	users, next, err = s.FindInactive("C1", time.Unix(200, 0), 25, next)
+	if err != nil {
+		t.Fatalf("got error %v, want nil", err)
+	}
+	if !reflect.DeepEqual(users, []string{"U2"}) {
+		t.Errorf("got users %v, want [U2]", users)
+	}
+	if next != nil {
+		t.Errorf("got nextCursor %v, want nil", next)
+	}
+
+	if len(fake.startKeys) != 2 {
+		t.Fatalf("got %d Query calls, want 2", len(fake.startKeys))
+	}
+	if fake.startKeys[0] != nil {
+		t.Errorf("got first ExclusiveStartKey %v, want nil", fake.startKeys[0])
+	}
+	if !reflect.DeepEqual(fake.startKeys[1], lastKey) {
+		t.Errorf("got second ExclusiveStartKey %v, want the full LastEvaluatedKey %v", fake.startKeys[1], lastKey)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"go.uber.org/zap"
+)
+
+// ChannelConfig is the tunable, per-channel behaviour for YELLCOP.
+type ChannelConfig struct {
+	// Number of words to only get a warning
+	Threshold int
+
+	// Period of inactivity before booting
+	InactiveTime time.Duration
+
+	// Disabled turns off yell-checking and inactivity kicks for the channel
+	Disabled bool
+
+	// Detector selects the YellDetector by name ("ascii", "unicode", "shout");
+	// empty defaults to "ascii". See detectorByName.
+	Detector string
+}
+
+// ConfigStore resolves and persists per-channel configuration. Get always
+// returns a usable config, falling back to the store's defaults when a
+// channel has no stored entry.
+type ConfigStore interface {
+	Get(channelID string) ChannelConfig
+	Put(channelID string, cfg ChannelConfig) error
+}
+
+const configTableChannelKey = "channel_id"
+
+// dynamoConfigStore is a ConfigStore backed by a DynamoDB table keyed on
+// channel_id, one item per channel.
+type dynamoConfigStore struct {
+	db       dynamodbiface.DynamoDBAPI
+	table    string
+	defaults ChannelConfig
+	log      *zap.Logger
+}
+
+func newDynamoConfigStore(sess *session.Session, table string, defaults ChannelConfig, log *zap.Logger) *dynamoConfigStore {
+	return &dynamoConfigStore{
+		db:       dynamodb.New(sess),
+		table:    table,
+		defaults: defaults,
+		log:      log,
+	}
+}
+
+func (s *dynamoConfigStore) Get(channelID string) ChannelConfig {
+	out, err := s.db.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(s.table),
+		Key: map[string]*dynamodb.AttributeValue{
+			configTableChannelKey: {S: aws.String(channelID)},
+		},
+	})
+	if err != nil {
+		s.log.Error("failed to get channel config", zap.String("channel", channelID), zap.Error(err))
+		return s.defaults
+	}
+	if out.Item == nil {
+		return s.defaults
+	}
+
+	cfg := s.defaults
+	if v := out.Item["threshold"]; v != nil && v.N != nil {
+		if n, err := strconv.Atoi(*v.N); err == nil {
+			cfg.Threshold = n
+		}
+	}
+	if v := out.Item["inactive_seconds"]; v != nil && v.N != nil {
+		if n, err := strconv.ParseInt(*v.N, 10, 64); err == nil {
+			cfg.InactiveTime = time.Duration(n) * time.Second
+		}
+	}
+	if v := out.Item["disabled"]; v != nil && v.BOOL != nil {
+		cfg.Disabled = *v.BOOL
+	}
+	if v := out.Item["detector"]; v != nil && v.S != nil {
+		cfg.Detector = *v.S
+	}
+	return cfg
+}
+
+func (s *dynamoConfigStore) Put(channelID string, cfg ChannelConfig) error {
+	_, err := s.db.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item: map[string]*dynamodb.AttributeValue{
+			configTableChannelKey: {S: aws.String(channelID)},
+			"threshold":           {N: aws.String(strconv.Itoa(cfg.Threshold))},
+			"inactive_seconds":    {N: aws.String(strconv.FormatInt(int64(cfg.InactiveTime/time.Second), 10))},
+			"disabled":            {BOOL: aws.Bool(cfg.Disabled)},
+			"detector":            {S: aws.String(cfg.Detector)},
+		},
+	})
+	return err
+}
+
+// cachedConfigEntry is a ChannelConfig with the time it should be refetched.
+type cachedConfigEntry struct {
+	cfg       ChannelConfig
+	expiresAt time.Time
+}
+
+// cachedConfigStore wraps a ConfigStore with an in-memory, per-channel TTL
+// cache so hot channels don't hit DynamoDB on every message.
+type cachedConfigStore struct {
+	next ConfigStore
+	ttl  time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedConfigEntry
+}
+
+func newCachedConfigStore(next ConfigStore, ttl time.Duration) *cachedConfigStore {
+	return &cachedConfigStore{
+		next:  next,
+		ttl:   ttl,
+		cache: make(map[string]cachedConfigEntry),
+	}
+}
+
+func (s *cachedConfigStore) Get(channelID string) ChannelConfig {
+	s.mu.Lock()
+	entry, ok := s.cache[channelID]
+	s.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.cfg
+	}
+
+	cfg := s.next.Get(channelID)
+
+	s.mu.Lock()
+	s.cache[channelID] = cachedConfigEntry{cfg: cfg, expiresAt: time.Now().Add(s.ttl)}
+	s.mu.Unlock()
+
+	return cfg
+}
+
+func (s *cachedConfigStore) Put(channelID string, cfg ChannelConfig) error {
+	if err := s.next.Put(channelID, cfg); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.cache[channelID] = cachedConfigEntry{cfg: cfg, expiresAt: time.Now().Add(s.ttl)}
+	s.mu.Unlock()
+
+	return nil
+}
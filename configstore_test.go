@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// fakeConfigDB implements dynamodbiface.DynamoDBAPI, serving GetItem from a
+// canned item (nil for "no stored entry") and recording the item passed to
+// PutItem.
+type fakeConfigDB struct {
+	dynamodbiface.DynamoDBAPI
+
+	item    map[string]*dynamodb.AttributeValue
+	getErr  error
+	putItem map[string]*dynamodb.AttributeValue
+}
+
+func (f *fakeConfigDB) GetItem(*dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	if f.getErr != nil {
+		return nil, f.getErr
+	}
+	return &dynamodb.GetItemOutput{Item: f.item}, nil
+}
+
+func (f *fakeConfigDB) PutItem(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	f.putItem = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func TestDynamoConfigStoreGetFallsBackToDefaultsWhenUnset(t *testing.T) {
+	defaults := ChannelConfig{Threshold: 3, InactiveTime: time.Hour, Detector: "ascii"}
+	s := &dynamoConfigStore{db: &fakeConfigDB{}, table: "config", defaults: defaults}
+
+	got := s.Get("C1")
+	if got != defaults {
+		t.Errorf("got %+v, want defaults %+v", got, defaults)
+	}
+}
+
+func TestDynamoConfigStoreGetReadsStoredItem(t *testing.T) {
+	fake := &fakeConfigDB{item: map[string]*dynamodb.AttributeValue{
+		"threshold":        {N: aws.String("5")},
+		"inactive_seconds": {N: aws.String("60")},
+		"disabled":         {BOOL: aws.Bool(true)},
+		"detector":         {S: aws.String("shout")},
+	}}
+	s := &dynamoConfigStore{db: fake, table: "config", defaults: ChannelConfig{Threshold: 3}}
+
+	got := s.Get("C1")
+	want := ChannelConfig{Threshold: 5, InactiveTime: time.Minute, Disabled: true, Detector: "shout"}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestDynamoConfigStorePutWritesAllFields(t *testing.T) {
+	fake := &fakeConfigDB{}
+	s := &dynamoConfigStore{db: fake, table: "config"}
+
+	cfg := ChannelConfig{Threshold: 5, InactiveTime: time.Minute, Disabled: true, Detector: "shout"}
+	if err := s.Put("C1", cfg); err != nil {
+		t.Fatalf("got error %v, want nil", err)
+	}
+
+	if got := *fake.putItem[configTableChannelKey].S; got != "C1" {
+		t.Errorf("got channel_id %q, want C1", got)
+	}
+	if got := *fake.putItem["threshold"].N; got != "5" {
+		t.Errorf("got threshold %q, want 5", got)
+	}
+	if got := *fake.putItem["inactive_seconds"].N; got != "60" {
+		t.Errorf("got inactive_seconds %q, want 60", got)
+	}
+	if got := *fake.putItem["disabled"].BOOL; !got {
+		t.Errorf("got disabled %v, want true", got)
+	}
+	if got := *fake.putItem["detector"].S; got != "shout" {
+		t.Errorf("got detector %q, want shout", got)
+	}
+}
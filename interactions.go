@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+const (
+	actionAppeal     = "yellcop_appeal"
+	actionYellLouder = "yellcop_yell_louder"
+)
+
+// appealValue packs the kicked user's channel and ID into a button value, so
+// the interaction handler knows who to re-invite without round-tripping
+// through a database.
+func appealValue(chID, uID string) string {
+	return chID + "|" + uID
+}
+
+func parseAppealValue(v string) (chID, uID string, ok bool) {
+	chID, uID, ok = strings.Cut(v, "|")
+	return
+}
+
+// handleInteraction processes a `block_actions` payload from the "Appeal"
+// and "I'll yell louder" buttons on a kick message.
+func (h *handler) handleInteraction(payload string) ([]byte, error) {
+	var cb slack.InteractionCallback
+	if err := json.Unmarshal([]byte(payload), &cb); err != nil {
+		return asLFUR(fmt.Sprintf("failed to parse interaction payload: %s", err), 400)
+	}
+
+	if cb.Type != slack.InteractionTypeBlockActions || len(cb.ActionCallback.BlockActions) == 0 {
+		return asLFUR("ok", 200)
+	}
+
+	action := cb.ActionCallback.BlockActions[0]
+	chID, uID, ok := parseAppealValue(action.Value)
+	if !ok {
+		h.log.Warn("ignoring interaction with malformed button value", zap.String("value", action.Value))
+		return asLFUR("ok", 200)
+	}
+
+	if cb.User.ID != uID {
+		h.log.Warn("ignoring interaction from user who wasn't kicked",
+			zap.String("clicked_by", cb.User.ID), zap.String("kicked_user", uID), zap.String("channel", chID))
+		return asLFUR("ok", 200)
+	}
+
+	switch action.ActionID {
+	case actionAppeal:
+		h.appeal(chID, uID)
+	case actionYellLouder:
+		h.log.Info("user committed to yell louder", zap.String("user", uID), zap.String("channel", chID))
+	}
+
+	return asLFUR("ok", 200)
+}
+
+// appeal re-invites a kicked user to the channel they were kicked from and
+// logs the appeal to the audit channel. Callers must have already verified
+// the appeal was clicked by uID themselves, not a bystander.
+func (h *handler) appeal(chID, uID string) {
+	if _, err := h.userAPI.InviteUsersToConversation(chID, uID); err != nil {
+		h.log.Error("failed to re-invite appealing user", zap.String("user", uID), zap.Error(err))
+		return
+	}
+	h.log.Info("appeal granted", zap.String("user", uID), zap.String("channel", chID))
+
+	if h.auditChannel == "" {
+		return
+	}
+	h.postMessage(h.auditChannel, uID, fmt.Sprintf("<@{user}> appealed their kick from <#%s> and was re-invited", chID))
+}
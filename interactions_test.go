@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func blockActionsPayload(actionID, value, userID string) string {
+	return fmt.Sprintf(`{
+		"type": "block_actions",
+		"user": {"id": %q},
+		"actions": [{"action_id": %q, "block_id": "yellcop_kick", "value": %q, "type": "button"}]
+	}`, userID, actionID, value)
+}
+
+func TestHandleInteractionAppealReinvitesTheKickedUser(t *testing.T) {
+	fake := &fakePostMessageAPI{}
+	h := &handler{log: zap.NewNop(), userAPI: fake, botAPI: fake}
+
+	payload := blockActionsPayload(actionAppeal, appealValue("C1", "U1"), "U1")
+	if _, err := h.handleInteraction(payload); err != nil {
+		t.Fatalf("got error %v, want nil", err)
+	}
+
+	if fake.invited != "U1" {
+		t.Errorf("got invited user %q, want U1", fake.invited)
+	}
+}
+
+func TestHandleInteractionAppealRejectsClickFromAnotherUser(t *testing.T) {
+	fake := &fakePostMessageAPI{}
+	h := &handler{log: zap.NewNop(), userAPI: fake, botAPI: fake}
+
+	payload := blockActionsPayload(actionAppeal, appealValue("C1", "U1"), "U2")
+	if _, err := h.handleInteraction(payload); err != nil {
+		t.Fatalf("got error %v, want nil", err)
+	}
+
+	if fake.invited != "" {
+		t.Errorf("got invited user %q, want none: a bystander must not be able to re-invite another user", fake.invited)
+	}
+}
+
+func TestHandleInteractionIgnoresMalformedButtonValue(t *testing.T) {
+	fake := &fakePostMessageAPI{}
+	h := &handler{log: zap.NewNop(), userAPI: fake, botAPI: fake}
+
+	payload := blockActionsPayload(actionAppeal, "not-a-pipe-separated-value", "U1")
+	if _, err := h.handleInteraction(payload); err != nil {
+		t.Fatalf("got error %v, want nil", err)
+	}
+
+	if fake.invited != "" {
+		t.Errorf("got invited user %q, want none", fake.invited)
+	}
+}
+
+func TestHandleInteractionIgnoresNonBlockActionsPayload(t *testing.T) {
+	h := &handler{log: zap.NewNop()}
+
+	if _, err := h.handleInteraction(`{"type": "view_submission"}`); err != nil {
+		t.Fatalf("got error %v, want nil", err)
+	}
+}
+
+func TestHandleInteractionRejectsUnparseablePayload(t *testing.T) {
+	h := &handler{log: zap.NewNop()}
+
+	if _, err := h.handleInteraction(`not json`); err != nil {
+		t.Fatalf("got error %v, want nil", err)
+	}
+}
@@ -3,11 +3,13 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"html"
 	"log"
 	"math/rand"
+	"net/http"
+	"net/url"
 	"os"
 	"regexp"
 	"strings"
@@ -79,20 +81,40 @@ type LambdaFunctionURLResponse struct {
 }
 
 type handler struct {
-	botAPI  *slack.Client
-	userAPI *slack.Client
+	botAPI  slackAPI
+	userAPI slackAPI
 	log     *zap.Logger
 
-	// Verification token, stored in SSM
-	verify string
+	// Verification token, stored in SSM. Deprecated by Slack in favour of
+	// request signing; only consulted when legacyVerify is set.
+	verify       string
+	legacyVerify bool
 
-	// Number of words to only get a warning
+	// Signing secret used to validate the X-Slack-Signature header, stored in SSM
+	signingSecret string
+
+	// Number of words to only get a warning. Default for channels with no
+	// per-channel config.
 	threshold int
 
-	// Period of inactivity before booting
+	// Period of inactivity before booting. Default for channels with no
+	// per-channel config.
 	inactiveTime time.Duration
 
-	chUsers []string
+	// Per-channel overrides for threshold, inactiveTime and enablement,
+	// tunable via the /yellcop slash command.
+	configs ConfigStore
+
+	// Channel appeals are logged to, empty to disable
+	auditChannel string
+
+	// Overrides per-channel detector selection when set; mainly for tests to
+	// inject a fake YellDetector.
+	detector YellDetector
+
+	// Per-(channel, user) last-message timestamps, consulted by checkHistory
+	// to find inactive users.
+	activity ActivityStore
 
 	// Messages
 	msgWarnings []string
@@ -116,41 +138,61 @@ func (h *handler) Invoke(ctx context.Context, b []byte) ([]byte, error) {
 
 	log.Println("HTTP method check passed")
 
-	options := slackevents.OptionVerifyToken(&slackevents.TokenComparator{VerificationToken: h.verify})
-	log.Println("Verifying token" + h.verify)
-	event, err := slackevents.ParseEvent(json.RawMessage(req.Body), options)
+	body := []byte(req.Body)
+	if req.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(req.Body)
+		if err != nil {
+			log.Println("Error decoding base64 body")
+			return asLFUR(fmt.Sprintf("failed to decode body: %s", err), 500)
+		}
+		body = decoded
+	}
+
+	var options slackevents.Option
+	if h.legacyVerify {
+		log.Println("Verifying token" + h.verify)
+		options = slackevents.OptionVerifyToken(&slackevents.TokenComparator{VerificationToken: h.verify})
+	} else {
+		if err := h.verifySignature(req.Headers, body); err != nil {
+			log.Println("Error verifying request signature")
+			return asLFUR(fmt.Sprintf("failed to verify signature: %s", err), 401)
+		}
+		log.Println("Request signature verified")
+		options = slackevents.OptionNoVerifyToken()
+	}
+
+	if isFormEncodedRequest(req.Headers) {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			log.Println("Error parsing form-encoded body")
+			return asLFUR(fmt.Sprintf("failed to parse body: %s", err), 400)
+		}
+		if payload := values.Get("payload"); payload != "" {
+			log.Println("Handling interaction")
+			return h.handleInteraction(payload)
+		}
+		log.Println("Handling slash command")
+		return h.handleSlashCommand(values)
+	}
+
+	event, err := slackevents.ParseEvent(json.RawMessage(body), options)
+	if err != nil {
+		log.Println("Error parsing event")
+		return asLFUR(fmt.Sprintf("failed to parse event: %s", err), 400)
+	}
 
 	switch event.Type {
 	case slackevents.URLVerification:
 		var r *slackevents.ChallengeResponse
-		if err = json.Unmarshal([]byte(req.Body), &r); err != nil {
+		if err = json.Unmarshal(body, &r); err != nil {
 			log.Println("Error parsing body for URL verification")
 			return asLFUR(fmt.Sprintf("failed to parse body: %s", err), 500)
 		}
 		log.Println("URL verification successful")
 		return asLFUR(r.Challenge, 200)
 
-	case slackevents.Cal	lbackEvent:
-		switch m := event.InnerEvent.Data.(type) {
-		case *slackevents.MessageEvent:
-			h.log.Debug(m.Text, zap.String("type", m.ChannelType), zap.String("user", m.User))
-			log.Println("Handling message event")
-			if m.ChannelType == "channel" {
-				out, kick := h.checkMessage(m.Text)
-				if kick {
-					h.kickUser(m.Channel, m.User, out)
-				} else if out != "" {
-					h.postMessage(m.Channel, m.User, out)
-				}
-				if rand.Intn(23) == time.Now().Hour() {
-					h.checkHistory(m.Channel)
-				}
-			}
-		case *slackevents.MemberJoinedChannelEvent:
-			h.log.Info("member joined", zap.String("user", m.User))
-			h.postMessage(m.Channel, m.User, welcomeMsg, slack.MsgOptionPostEphemeral(m.User))
-			log.Println("Handling member joined event")
-		}
+	case slackevents.CallbackEvent:
+		h.HandleEvent(ctx, event)
 	default:
 		log.Printf("Missing type implementation: %s", event.Type)
 		return asLFUR(fmt.Sprintf("missing type implementation: %s", event.Type), 501)
@@ -159,20 +201,79 @@ func (h *handler) Invoke(ctx context.Context, b []byte) ([]byte, error) {
 	return asLFUR("ok", 200)
 }
 
-func (h *handler) checkMessage(msg string) (string, bool) {
+// HandleEvent is the transport-agnostic event-processing core shared by the
+// Lambda (Invoke) and Socket Mode (runSocketMode) entrypoints.
+func (h *handler) HandleEvent(ctx context.Context, event slackevents.EventsAPIEvent) {
+	switch m := event.InnerEvent.Data.(type) {
+	case *slackevents.MessageEvent:
+		h.log.Debug(m.Text, zap.String("type", m.ChannelType), zap.String("user", m.User))
+		log.Println("Handling message event")
+		if m.ChannelType == "channel" {
+			if h.activity != nil {
+				if err := h.activity.Touch(m.Channel, m.User, time.Now()); err != nil {
+					h.log.Error("failed to record activity", zap.Error(err))
+				}
+			}
+			out, kick := h.checkMessage(m.Channel, m.Text)
+			if kick {
+				h.kickUser(m.Channel, m.User, out)
+			} else if out != "" {
+				h.postMessage(m.Channel, m.User, out)
+			}
+			if rand.Intn(23) == time.Now().Hour() {
+				h.checkHistory(m.Channel)
+			}
+		}
+	case *slackevents.MemberJoinedChannelEvent:
+		h.log.Info("member joined", zap.String("user", m.User))
+		h.postMessage(m.Channel, m.User, welcomeMsg, slack.MsgOptionPostEphemeral(m.User))
+		log.Println("Handling member joined event")
+	}
+}
+
+// verifySignature validates the X-Slack-Signature / X-Slack-Request-Timestamp
+// headers against body using h.signingSecret, rejecting requests older than
+// 5 minutes (enforced by slack.NewSecretsVerifier).
+func (h *handler) verifySignature(headers map[string]string, body []byte) error {
+	hdr := make(http.Header, len(headers))
+	for k, v := range headers {
+		hdr.Set(k, v)
+	}
+
+	sv, err := slack.NewSecretsVerifier(hdr, h.signingSecret)
+	if err != nil {
+		return err
+	}
+	if _, err := sv.Write(body); err != nil {
+		return err
+	}
+	return sv.Ensure()
+}
+
+func (h *handler) checkMessage(chID, msg string) (string, bool) {
+	cfg := h.channelConfig(chID)
+	if cfg.Disabled {
+		return "", false
+	}
+
 	// Skip slack messages
 	for _, m := range skipMessages {
 		if strings.Contains(msg, m) {
 			return "", false
 		}
 	}
+	detector := h.detector
+	if detector == nil {
+		detector = detectorByName(cfg.Detector)
+	}
+
 	var count int
 	for _, word := range strings.Fields(msg) {
-		if !isYell(word) {
+		if !detector.IsYell(word) {
 			count++
 		}
 	}
-	if count > h.threshold {
+	if count > cfg.Threshold {
 		return randomMessage(h.msgKick), true
 	} else if count > 0 {
 		return randomMessage(h.msgWarnings), false
@@ -180,92 +281,96 @@ func (h *handler) checkMessage(msg string) (string, bool) {
 	return "", false
 }
 
-func isYell(s string) bool {
-	// Remove emojis first
-	s = emojiRE.ReplaceAllString(s, "")
-	if urlRE.MatchString(s) {
-		return true
+// channelConfig resolves the effective config for chID, falling back to the
+// handler's defaults when no per-channel config store is configured (e.g. in
+// tests).
+func (h *handler) channelConfig(chID string) ChannelConfig {
+	if h.configs == nil {
+		return ChannelConfig{Threshold: h.threshold, InactiveTime: h.inactiveTime}
 	}
-	s = html.UnescapeString(s)
-	return strings.ToUpper(s) == s
+	return h.configs.Get(chID)
 }
 
+// kickUser removes uID from chID and DMs them the kick message with Appeal
+// and "I'll yell louder" buttons. The message goes to a DM, not chID,
+// because a kicked user generally can't see or act on messages in a channel
+// they're no longer a member of.
 func (h *handler) kickUser(chID, uID, msg string) {
 	h.log.Info("kicking", zap.String("user", uID), zap.String("message", msg))
 	if err := h.userAPI.KickUserFromConversation(chID, uID); err != nil {
 		h.log.Error("failed to kick", zap.Error(err))
 		return
 	}
-	h.postMessage(chID, uID, msg)
+
+	dm, _, _, err := h.botAPI.OpenConversation(&slack.OpenConversationParameters{Users: []string{uID}})
+	if err != nil {
+		h.log.Error("failed to open DM with kicked user", zap.String("user", uID), zap.Error(err))
+		return
+	}
+
+	text := strings.ToUpper(strings.ReplaceAll(msg, "{user}", uID))
+	value := appealValue(chID, uID)
+	blocks := slack.MsgOptionBlocks(
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil),
+		slack.NewActionBlock("yellcop_kick",
+			slack.NewButtonBlockElement(actionAppeal, value, slack.NewTextBlockObject(slack.PlainTextType, "Appeal", false, false)),
+			slack.NewButtonBlockElement(actionYellLouder, value, slack.NewTextBlockObject(slack.PlainTextType, "I'll yell louder", false, false)),
+		),
+	)
+	if _, _, err := h.botAPI.PostMessage(dm.ID, slack.MsgOptionText(text, false), blocks); err != nil {
+		h.log.Error("failed to post", zap.Error(err))
+	}
 }
 
 func (h *handler) postMessage(chID, uID, msg string, opts ...slack.MsgOption) {
-	opts = append(opts, slack.MsgOptionText(strings.ToUpper(strings.ReplaceAll(msg, "{user}", uID)), false))
+	text := strings.ToUpper(strings.ReplaceAll(msg, "{user}", uID))
+	blocks := slack.MsgOptionBlocks(slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil))
+	opts = append(opts, slack.MsgOptionText(text, false), blocks)
 	if _, _, err := h.botAPI.PostMessage(chID, opts...); err != nil {
 		h.log.Error("failed to post", zap.Error(err))
 	}
 }
-func (h *handler) fetchChannelUsers(chID string) {
-	var err error
-	h.chUsers = make([]string, 0)
-	hasMore := true
-	cursor := ""
-	for hasMore {
-		var members []string
-		members, cursor, err = h.botAPI.GetUsersInConversation(&slack.GetUsersInConversationParameters{
-			ChannelID: chID,
-			Cursor:    cursor,
-			Limit:     200,
-		})
-		if err != nil {
-			h.log.Error("failed to get users", zap.Error(err))
-			return
-		}
-		hasMore = (cursor != "")
-		h.chUsers = append(h.chUsers, members...)
-	}
-
-	h.log.Info("channel users fetched", zap.Int("count", len(h.chUsers)))
-}
 
+// checkHistory finds a user in chID who hasn't posted since cfg.InactiveTime
+// and warns them. Users are drawn from h.activity, which is kept up to date
+// by HandleEvent on every MessageEvent, oldest-last-seen first.
 func (h *handler) checkHistory(chID string) {
-	var err error
-	startTime := time.Now().Add(-1 * h.inactiveTime)
-
-	if len(h.chUsers) == 0 {
-		h.fetchChannelUsers(chID)
-	}
-
-	// Select a victim at random
-	rand.Shuffle(len(h.chUsers), func(i, j int) {
-		h.chUsers[i], h.chUsers[j] = h.chUsers[j], h.chUsers[i]
-	})
-	uID := h.chUsers[0]
-	info, err := h.botAPI.GetUserInfo(uID)
-	if err != nil {
-		h.log.Error("failed to get user info", zap.Error(err))
+	cfg := h.channelConfig(chID)
+	if cfg.Disabled || h.activity == nil {
 		return
 	}
 
-	if info.IsBot {
-		h.log.Info("ignoring history for bot")
-		return
-	}
+	cutoff := time.Now().Add(-1 * cfg.InactiveTime)
 
-	// Count their messages since the inactivity time
-	query := fmt.Sprintf("from:<@%s> in:%s after:%s", uID, chID, startTime.Format(time.RFC3339))
-	resp, err := h.userAPI.SearchMessages(query, slack.NewSearchParameters())
-	if err != nil {
-		h.log.Error("failed to search messages", zap.Error(err))
-		return
-	}
+	var cursor ActivityCursor
+	for {
+		users, next, err := h.activity.FindInactive(chID, cutoff, 25, cursor)
+		if err != nil {
+			h.log.Error("failed to query activity store", zap.Error(err))
+			return
+		}
+
+		for _, uID := range users {
+			info, err := h.botAPI.GetUserInfo(uID)
+			if err != nil {
+				h.log.Error("failed to get user info", zap.Error(err))
+				continue
+			}
+			if info.IsBot {
+				continue
+			}
+
+			h.log.Warn("checked history", zap.String("user", uID), zap.String("channel", chID))
+			//h.kickUser(chID, uID, randomMessage(h.msgInactive))
+			h.postMessage(chID, uID, "non-yelling lurker detected, warning <@{user}>")
+			return
+		}
 
-	h.log.Warn("checked history", zap.String("user", uID), zap.Int("count", resp.TotalCount), zap.String("query", query))
-	if resp.TotalCount == 0 {
-		//h.kickUser(chID, uID, randomMessage(h.msgInactive))
-		h.postMessage(chID, uID, "non-yelling lurker detected, warning <@{user}>")
+		if next == nil {
+			return
+		}
+		cursor = next
 	}
-	return
 }
 
 // asLFUR simplifies returning an LambdaFunctionURLResponse inline.
@@ -348,34 +453,73 @@ func main() {
 		}
 	}
 
-	h.verify, err = ssmGet("/yellcop/tokens/slack/verification-token", true)
+	h.msgWarnings = warningMessages
+	h.msgKick = failureMessages
+	h.msgInactive = inactiveMessages
+	h.auditChannel = os.Getenv("AUDIT_CHANNEL")
+
+	configTable := os.Getenv("CONFIG_TABLE")
+	if configTable == "" {
+		configTable = "yellcop-channel-config"
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config: aws.Config{Region: aws.String("ap-southeast-2")},
+	})
 	if err != nil {
-		logger.Fatal("failed to fetch verify", zap.Error(err))
+		logger.Fatal("failed to create aws session", zap.Error(err))
+	}
+	h.configs = newCachedConfigStore(
+		newDynamoConfigStore(sess, configTable, ChannelConfig{Threshold: h.threshold, InactiveTime: h.inactiveTime}, logger),
+		time.Minute,
+	)
+
+	activityTable := os.Getenv("ACTIVITY_TABLE")
+	if activityTable == "" {
+		activityTable = "yellcop-channel-activity"
+	}
+	h.activity = newDynamoActivityStore(sess, activityTable, logger)
+
+	rateLimitCeiling := rateLimitCeilingFromEnv()
+	onThrottle := cloudwatchThrottleMetric(sess, logger)
+
+	if os.Getenv("SOCKET_MODE") != "" {
+		runSocketModeMain(h, logger, rateLimitCeiling, onThrottle)
+		return
+	}
+
+	h.legacyVerify = os.Getenv("LEGACY_SLACK_VERIFY") != ""
+	if h.legacyVerify {
+		h.verify, err = ssmGet("/yellcop/tokens/slack/verification-token", true)
+		if err != nil {
+			logger.Fatal("failed to fetch verify", zap.Error(err))
+		}
+	} else {
+		h.signingSecret, err = ssmGet("/yellcop/tokens/slack/signing-secret", true)
+		if err != nil {
+			logger.Fatal("failed to fetch signing secret", zap.Error(err))
+		}
 	}
 
 	botToken, err := ssmGet("/yellcop/tokens/slack/bot-token", true)
 	if err != nil {
 		logger.Fatal("failed to fetch bot token", zap.Error(err))
 	}
-	h.botAPI = slack.New(botToken)
+	h.botAPI = newRetryingSlackClient(slack.New(botToken), rateLimitCeiling, onThrottle, logger)
 
 	userToken, err := ssmGet("/yellcop/tokens/slack/user-token", true)
 	if err != nil {
 		logger.Fatal("failed to fetch user token", zap.Error(err))
 	}
-	h.userAPI = slack.New(userToken)
+	h.userAPI = newRetryingSlackClient(slack.New(userToken), rateLimitCeiling, onThrottle, logger)
 
-	h.msgWarnings = warningMessages
 	if w, err := ssmGet("/yellcop/warnings", false); err == nil {
 		h.msgWarnings = append(h.msgWarnings, strings.Split(w, "|")...)
 	}
 
-	h.msgKick = failureMessages
 	if f, err := ssmGet("/yellcop/failures", false); err == nil {
 		h.msgKick = append(h.msgKick, strings.Split(f, "|")...)
 	}
 
-	h.msgInactive = inactiveMessages
 	if f, err := ssmGet("/yellcop/inactive", false); err == nil {
 		h.msgInactive = append(h.msgInactive, strings.Split(f, "|")...)
 	}
@@ -50,11 +50,11 @@ func TestCheckMessage(t *testing.T) {
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
 			h := &handler{
-				threshold: 1,
-				warnings:  []string{warn},
-				failures:  []string{fail},
+				threshold:   1,
+				msgWarnings: []string{warn},
+				msgKick:     []string{fail},
 			}
-			actual, kick := h.checkMessage(tt.msg)
+			actual, kick := h.checkMessage("C1", tt.msg)
 			if kick != tt.kick {
 				t.Errorf("got %t, want %t", kick, tt.kick)
 			}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+// slackAPI is the subset of *slack.Client used by handler. It's satisfied by
+// *slack.Client directly and by retryingSlackClient below, and lets tests
+// inject a fake.
+type slackAPI interface {
+	PostMessage(channelID string, options ...slack.MsgOption) (string, string, error)
+	KickUserFromConversation(channelID, user string) error
+	GetUserInfo(user string) (*slack.User, error)
+	InviteUsersToConversation(channelID string, users ...string) (*slack.Channel, error)
+	OpenConversation(params *slack.OpenConversationParameters) (*slack.Channel, bool, bool, error)
+}
+
+const defaultRateLimitCeiling = 2 * time.Minute
+
+// rateLimitCeilingFromEnv reads the total time a retryingSlackClient is
+// willing to spend backing off a single call before giving up.
+func rateLimitCeilingFromEnv() time.Duration {
+	if s := os.Getenv("RATE_LIMIT_CEILING"); s != "" {
+		if d, err := time.ParseDuration(s); err == nil {
+			return d
+		}
+	}
+	return defaultRateLimitCeiling
+}
+
+// retryingSlackClient wraps a slackAPI, retrying calls that come back as
+// slack.RateLimitedError with exponential backoff and jitter on top of
+// Slack's requested RetryAfter, up to ceiling of total wait time.
+type retryingSlackClient struct {
+	next       slackAPI
+	ceiling    time.Duration
+	onThrottle func(method string)
+	log        *zap.Logger
+}
+
+func newRetryingSlackClient(next slackAPI, ceiling time.Duration, onThrottle func(method string), log *zap.Logger) *retryingSlackClient {
+	return &retryingSlackClient{next: next, ceiling: ceiling, onThrottle: onThrottle, log: log}
+}
+
+func (c *retryingSlackClient) withRetry(method string, call func() error) error {
+	var elapsed time.Duration
+	for attempt := 0; ; attempt++ {
+		err := call()
+
+		var rlErr *slack.RateLimitedError
+		if !errors.As(err, &rlErr) {
+			return err
+		}
+
+		delay := rlErr.RetryAfter + backoffJitter(attempt)
+		if elapsed+delay > c.ceiling {
+			c.log.Warn("giving up on rate-limited call: backoff ceiling reached",
+				zap.String("method", method), zap.Int("attempt", attempt))
+			return err
+		}
+
+		if c.onThrottle != nil {
+			c.onThrottle(method)
+		}
+		c.log.Warn("rate limited, backing off",
+			zap.String("method", method), zap.Duration("delay", delay), zap.Int("attempt", attempt))
+
+		time.Sleep(delay)
+		elapsed += delay
+	}
+}
+
+// backoffJitter returns exponential backoff (500ms * 2^attempt) plus up to an
+// equal amount of jitter, so concurrent retries don't all land on Slack at
+// the same instant.
+func backoffJitter(attempt int) time.Duration {
+	base := 500 * time.Millisecond * time.Duration(int64(1)<<uint(attempt))
+	return base + time.Duration(rand.Int63n(int64(base)+1))
+}
+
+func (c *retryingSlackClient) PostMessage(channelID string, options ...slack.MsgOption) (channel, ts string, err error) {
+	err = c.withRetry("PostMessage", func() error {
+		var innerErr error
+		channel, ts, innerErr = c.next.PostMessage(channelID, options...)
+		return innerErr
+	})
+	return
+}
+
+func (c *retryingSlackClient) KickUserFromConversation(channelID, user string) error {
+	return c.withRetry("KickUserFromConversation", func() error {
+		return c.next.KickUserFromConversation(channelID, user)
+	})
+}
+
+func (c *retryingSlackClient) GetUserInfo(user string) (info *slack.User, err error) {
+	err = c.withRetry("GetUserInfo", func() error {
+		var innerErr error
+		info, innerErr = c.next.GetUserInfo(user)
+		return innerErr
+	})
+	return
+}
+
+func (c *retryingSlackClient) InviteUsersToConversation(channelID string, users ...string) (ch *slack.Channel, err error) {
+	err = c.withRetry("InviteUsersToConversation", func() error {
+		var innerErr error
+		ch, innerErr = c.next.InviteUsersToConversation(channelID, users...)
+		return innerErr
+	})
+	return
+}
+
+func (c *retryingSlackClient) OpenConversation(params *slack.OpenConversationParameters) (ch *slack.Channel, noOp, alreadyOpen bool, err error) {
+	err = c.withRetry("OpenConversation", func() error {
+		var innerErr error
+		ch, noOp, alreadyOpen, innerErr = c.next.OpenConversation(params)
+		return innerErr
+	})
+	return
+}
+
+// cloudwatchThrottleMetric returns an onThrottle callback that emits a
+// YELLCOP/SlackRateLimitRetry count metric, dimensioned by API method, so
+// operators can spot channels triggering tier-2/tier-3 limits (notably
+// users.info, which is hit once per checkHistory scan).
+func cloudwatchThrottleMetric(sess *session.Session, log *zap.Logger) func(method string) {
+	cw := cloudwatch.New(sess)
+	return func(method string) {
+		_, err := cw.PutMetricData(&cloudwatch.PutMetricDataInput{
+			Namespace: aws.String("YELLCOP"),
+			MetricData: []*cloudwatch.MetricDatum{
+				{
+					MetricName: aws.String("SlackRateLimitRetry"),
+					Dimensions: []*cloudwatch.Dimension{
+						{Name: aws.String("Method"), Value: aws.String(method)},
+					},
+					Unit:  aws.String(cloudwatch.StandardUnitCount),
+					Value: aws.Float64(1),
+				},
+			},
+		})
+		if err != nil {
+			log.Error("failed to emit rate limit metric", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+// fakePostMessageAPI implements slackAPI, failing PostMessage with a
+// RateLimitedError failTimes times before succeeding, and recording the
+// last user invited via InviteUsersToConversation.
+type fakePostMessageAPI struct {
+	failTimes int
+	calls     int
+	invited   string
+}
+
+func (f *fakePostMessageAPI) PostMessage(channelID string, options ...slack.MsgOption) (string, string, error) {
+	f.calls++
+	if f.calls <= f.failTimes {
+		return "", "", &slack.RateLimitedError{RetryAfter: 0}
+	}
+	return channelID, "123.456", nil
+}
+
+func (f *fakePostMessageAPI) KickUserFromConversation(channelID, user string) error { return nil }
+func (f *fakePostMessageAPI) GetUserInfo(user string) (*slack.User, error)          { return nil, nil }
+func (f *fakePostMessageAPI) InviteUsersToConversation(channelID string, users ...string) (*slack.Channel, error) {
+	if len(users) > 0 {
+		f.invited = users[0]
+	}
+	return nil, nil
+}
+func (f *fakePostMessageAPI) OpenConversation(params *slack.OpenConversationParameters) (*slack.Channel, bool, bool, error) {
+	return nil, false, false, nil
+}
+
+func TestRetryingSlackClientRetriesRateLimitedCalls(t *testing.T) {
+	fake := &fakePostMessageAPI{failTimes: 1}
+	var throttled int
+	c := newRetryingSlackClient(fake, time.Second, func(string) { throttled++ }, zap.NewNop())
+
+	if _, _, err := c.PostMessage("C1"); err != nil {
+		t.Fatalf("got error %v, want nil", err)
+	}
+	if fake.calls != 2 {
+		t.Errorf("got %d calls, want 2", fake.calls)
+	}
+	if throttled != 1 {
+		t.Errorf("got %d onThrottle calls, want 1", throttled)
+	}
+}
+
+func TestRetryingSlackClientGivesUpAtCeiling(t *testing.T) {
+	fake := &fakePostMessageAPI{failTimes: 100}
+	c := newRetryingSlackClient(fake, 0, nil, zap.NewNop())
+
+	if _, _, err := c.PostMessage("C1"); err == nil {
+		t.Fatal("got nil error, want a rate limited error")
+	}
+	if fake.calls != 1 {
+		t.Errorf("got %d calls, want 1", fake.calls)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// isFormEncodedRequest reports whether req carries a Slack slash command or
+// interaction payload (application/x-www-form-urlencoded) rather than an
+// Events API JSON envelope.
+func isFormEncodedRequest(headers map[string]string) bool {
+	for k, v := range headers {
+		if strings.EqualFold(k, "content-type") {
+			return strings.HasPrefix(strings.ToLower(v), "application/x-www-form-urlencoded")
+		}
+	}
+	return false
+}
+
+// handleSlashCommand processes the `/yellcop` slash command, letting channel
+// admins tune threshold, inactivity and enablement without a redeploy.
+func (h *handler) handleSlashCommand(values url.Values) ([]byte, error) {
+	chID := values.Get("channel_id")
+	if chID == "" {
+		return asLFUR("missing channel_id", 400)
+	}
+	if h.configs == nil {
+		return asLFUR("per-channel config is not enabled", 501)
+	}
+
+	args := strings.Fields(values.Get("text"))
+	if len(args) == 0 {
+		return asLFUR("usage: /yellcop threshold <n> | inactivity <duration> | detector <name> | enable | disable", 200)
+	}
+
+	cfg := h.channelConfig(chID)
+
+	switch args[0] {
+	case "threshold":
+		if len(args) != 2 {
+			return asLFUR("usage: /yellcop threshold <n>", 200)
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			return asLFUR(fmt.Sprintf("invalid threshold %q: %s", args[1], err), 200)
+		}
+		cfg.Threshold = n
+
+	case "inactivity":
+		if len(args) != 2 {
+			return asLFUR("usage: /yellcop inactivity <duration, e.g. 14d or 72h>", 200)
+		}
+		d, err := parseInactivityDuration(args[1])
+		if err != nil {
+			return asLFUR(fmt.Sprintf("invalid duration %q: %s", args[1], err), 200)
+		}
+		cfg.InactiveTime = d
+
+	case "enable":
+		cfg.Disabled = false
+
+	case "disable":
+		cfg.Disabled = true
+
+	case "detector":
+		if len(args) != 2 {
+			return asLFUR("usage: /yellcop detector ascii|unicode|shout", 200)
+		}
+		switch args[1] {
+		case "ascii", "unicode", "shout":
+			cfg.Detector = args[1]
+		default:
+			return asLFUR(fmt.Sprintf("unknown detector %q: want ascii, unicode or shout", args[1]), 200)
+		}
+
+	default:
+		return asLFUR(fmt.Sprintf("unknown /yellcop command: %s", args[0]), 200)
+	}
+
+	if err := h.configs.Put(chID, cfg); err != nil {
+		h.log.Error("failed to save channel config")
+		return asLFUR(fmt.Sprintf("failed to save config: %s", err), 500)
+	}
+
+	return asLFUR("ok", 200)
+}
+
+// parseInactivityDuration extends time.ParseDuration with a "d" (days) unit,
+// since Slack admins naturally reach for "/yellcop inactivity 14d".
+func parseInactivityDuration(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		n, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
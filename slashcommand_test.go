@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fakeConfigStore is a ConfigStore backed by an in-memory map, for exercising
+// handleSlashCommand without DynamoDB.
+type fakeConfigStore struct {
+	cfgs map[string]ChannelConfig
+
+	putErr error
+}
+
+func (f *fakeConfigStore) Get(channelID string) ChannelConfig {
+	return f.cfgs[channelID]
+}
+
+func (f *fakeConfigStore) Put(channelID string, cfg ChannelConfig) error {
+	if f.putErr != nil {
+		return f.putErr
+	}
+	if f.cfgs == nil {
+		f.cfgs = make(map[string]ChannelConfig)
+	}
+	f.cfgs[channelID] = cfg
+	return nil
+}
+
+func TestParseInactivityDuration(t *testing.T) {
+	tests := map[string]struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		"days":         {"14d", 14 * 24 * time.Hour, false},
+		"zero days":    {"0d", 0, false},
+		"plain":        {"72h", 72 * time.Hour, false},
+		"minutes":      {"30m", 30 * time.Minute, false},
+		"bad days":     {"xd", 0, true},
+		"bad duration": {"not-a-duration", 0, true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseInactivityDuration(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got nil error, want one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %v, want nil", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleSlashCommand(t *testing.T) {
+	tests := map[string]struct {
+		text       string
+		wantStatus int
+		wantCfg    ChannelConfig
+	}{
+		"threshold": {
+			"threshold 5",
+			200,
+			ChannelConfig{Threshold: 5},
+		},
+		"threshold bad arg count": {
+			"threshold",
+			200,
+			ChannelConfig{},
+		},
+		"threshold not a number": {
+			"threshold abc",
+			200,
+			ChannelConfig{},
+		},
+		"inactivity days": {
+			"inactivity 14d",
+			200,
+			ChannelConfig{InactiveTime: 14 * 24 * time.Hour},
+		},
+		"inactivity bad duration": {
+			"inactivity whenever",
+			200,
+			ChannelConfig{},
+		},
+		"enable": {
+			"enable",
+			200,
+			ChannelConfig{Disabled: false},
+		},
+		"disable": {
+			"disable",
+			200,
+			ChannelConfig{Disabled: true},
+		},
+		"detector valid": {
+			"detector shout",
+			200,
+			ChannelConfig{Detector: "shout"},
+		},
+		"detector unknown": {
+			"detector nope",
+			200,
+			ChannelConfig{},
+		},
+		"unknown command": {
+			"frobnicate",
+			200,
+			ChannelConfig{},
+		},
+		"empty text": {
+			"",
+			200,
+			ChannelConfig{},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			store := &fakeConfigStore{cfgs: map[string]ChannelConfig{}}
+			h := &handler{log: zap.NewNop(), configs: store}
+
+			values := url.Values{"channel_id": {"C1"}, "text": {tt.text}}
+			body, err := h.handleSlashCommand(values)
+			if err != nil {
+				t.Fatalf("got error %v, want nil", err)
+			}
+
+			var resp LambdaFunctionURLResponse
+			if err := json.Unmarshal(body, &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("got status %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := store.cfgs["C1"]; got != tt.wantCfg {
+				t.Errorf("got stored config %+v, want %+v", got, tt.wantCfg)
+			}
+		})
+	}
+}
+
+func TestHandleSlashCommandMissingChannelID(t *testing.T) {
+	h := &handler{log: zap.NewNop(), configs: &fakeConfigStore{}}
+
+	body, err := h.handleSlashCommand(url.Values{"text": {"enable"}})
+	if err != nil {
+		t.Fatalf("got error %v, want nil", err)
+	}
+
+	var resp LambdaFunctionURLResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.StatusCode != 400 {
+		t.Errorf("got status %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestHandleSlashCommandRequiresConfigStore(t *testing.T) {
+	h := &handler{log: zap.NewNop()}
+
+	// asLFUR returns a non-nil error alongside the body for 5xx statuses.
+	body, err := h.handleSlashCommand(url.Values{"channel_id": {"C1"}, "text": {"enable"}})
+	if err == nil {
+		t.Fatal("got nil error, want one for a 501 response")
+	}
+
+	var resp LambdaFunctionURLResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.StatusCode != 501 {
+		t.Errorf("got status %d, want 501", resp.StatusCode)
+	}
+}
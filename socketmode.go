@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+	"go.uber.org/zap"
+)
+
+// runSocketModeMain wires up a handler with tokens read from the environment
+// and runs it via Socket Mode until the connection dies. It is the Socket
+// Mode counterpart of the Lambda bootstrapping in main.
+func runSocketModeMain(h *handler, logger *zap.Logger, rateLimitCeiling time.Duration, onThrottle func(string)) {
+	botToken := os.Getenv("SLACK_BOT_TOKEN")
+	appToken := os.Getenv("SLACK_APP_TOKEN")
+	if botToken == "" || appToken == "" {
+		logger.Fatal("SOCKET_MODE requires SLACK_BOT_TOKEN and SLACK_APP_TOKEN")
+	}
+
+	userToken := os.Getenv("SLACK_USER_TOKEN")
+	if userToken == "" {
+		userToken = botToken
+	}
+	h.userAPI = newRetryingSlackClient(slack.New(userToken), rateLimitCeiling, onThrottle, logger)
+
+	if err := h.runSocketMode(context.Background(), botToken, appToken, rateLimitCeiling, onThrottle); err != nil {
+		logger.Fatal("socket mode connection failed", zap.Error(err))
+	}
+}
+
+// runSocketMode connects to Slack via Socket Mode instead of Lambda, so
+// YELLCOP can run on dev laptops and containers without exposing a public
+// URL. Events API envelopes are dispatched into the same HandleEvent core
+// used by the Lambda entrypoint.
+func (h *handler) runSocketMode(ctx context.Context, botToken, appToken string, rateLimitCeiling time.Duration, onThrottle func(string)) error {
+	rawBotAPI := slack.New(botToken, slack.OptionAppLevelToken(appToken))
+	client := socketmode.New(rawBotAPI)
+	h.botAPI = newRetryingSlackClient(rawBotAPI, rateLimitCeiling, onThrottle, h.log)
+
+	go func() {
+		for evt := range client.Events {
+			switch evt.Type {
+			case socketmode.EventTypeEventsAPI:
+				event, ok := evt.Data.(slackevents.EventsAPIEvent)
+				if !ok {
+					h.log.Warn("ignored socket mode event: unexpected payload", zap.Any("type", evt.Type))
+					continue
+				}
+				if evt.Request != nil {
+					client.Ack(*evt.Request)
+				}
+				h.HandleEvent(ctx, event)
+			case socketmode.EventTypeConnecting:
+				h.log.Info("connecting to slack via socket mode")
+			case socketmode.EventTypeConnectionError:
+				h.log.Error("socket mode connection error")
+			case socketmode.EventTypeConnected:
+				h.log.Info("connected to slack via socket mode")
+			}
+		}
+	}()
+
+	return client.RunContext(ctx)
+}
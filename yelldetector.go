@@ -0,0 +1,109 @@
+package main
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// YellDetector decides whether a single word counts as "yelling".
+type YellDetector interface {
+	IsYell(s string) bool
+}
+
+const defaultShoutRatio = 0.7
+
+// cleanWord strips emoji shortcodes and HTML entities before a word is
+// handed to a YellDetector, and reports whether it should be treated as
+// yelling outright (URLs are exempt from case checks).
+func cleanWord(s string) (string, bool) {
+	s = emojiRE.ReplaceAllString(s, "")
+	if urlRE.MatchString(s) {
+		return "", true
+	}
+	return html.UnescapeString(s), false
+}
+
+// asciiYellDetector is the original behaviour: a word yells if it has no
+// lowercase ASCII letters.
+type asciiYellDetector struct{}
+
+func (asciiYellDetector) IsYell(s string) bool {
+	cleaned, yell := cleanWord(s)
+	if yell {
+		return true
+	}
+	return strings.ToUpper(cleaned) == cleaned
+}
+
+// unicodeYellDetector is Unicode-aware: it uses unicode.IsUpper/IsLower so
+// Cyrillic, Greek and other cased scripts are handled correctly. Caseless
+// scripts (CJK, Arabic, digits, punctuation) have no lowercase runes to find,
+// so they're treated as yelling by default.
+type unicodeYellDetector struct{}
+
+func (unicodeYellDetector) IsYell(s string) bool {
+	cleaned, yell := cleanWord(s)
+	if yell {
+		return true
+	}
+	for _, r := range cleaned {
+		if unicode.IsLower(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// shoutRatioYellDetector flags a word as yelling once more than Ratio of its
+// cased runes are upper case, rather than requiring all of them to be.
+// Caseless words fall back to unicodeYellDetector's default of yelling.
+type shoutRatioYellDetector struct {
+	Ratio float64
+}
+
+func (d shoutRatioYellDetector) IsYell(s string) bool {
+	cleaned, yell := cleanWord(s)
+	if yell {
+		return true
+	}
+
+	var cased, upper int
+	for _, r := range cleaned {
+		switch {
+		case unicode.IsUpper(r):
+			cased++
+			upper++
+		case unicode.IsLower(r):
+			cased++
+		}
+	}
+	if cased == 0 {
+		return true
+	}
+
+	ratio := d.Ratio
+	if ratio == 0 {
+		ratio = defaultShoutRatio
+	}
+	return float64(upper)/float64(cased) > ratio
+}
+
+var (
+	asciiDetector   YellDetector = asciiYellDetector{}
+	unicodeDetector YellDetector = unicodeYellDetector{}
+	shoutDetector   YellDetector = shoutRatioYellDetector{Ratio: defaultShoutRatio}
+)
+
+// detectorByName resolves a ChannelConfig.Detector name to a YellDetector,
+// defaulting to the original ASCII behaviour for an empty or unknown name.
+func detectorByName(name string) YellDetector {
+	switch name {
+	case "unicode":
+		return unicodeDetector
+	case "shout":
+		return shoutDetector
+	default:
+		return asciiDetector
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestYellDetectors(t *testing.T) {
+	tests := map[string]struct {
+		detector YellDetector
+		word     string
+		yell     bool
+	}{
+		"ascii upper":          {asciiDetector, "YELL", true},
+		"ascii lower":          {asciiDetector, "talk", false},
+		"ascii url":            {asciiDetector, "http://example.com", true},
+		"unicode cyrillic up":  {unicodeDetector, "ПРИВЕТ", true},
+		"unicode cyrillic low": {unicodeDetector, "привет", false},
+		"unicode cjk":          {unicodeDetector, "你好", true},
+		"shout mostly upper":   {shoutDetector, "YELLo", true},
+		"shout mostly lower":   {shoutDetector, "Yellow", false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if actual := tt.detector.IsYell(tt.word); actual != tt.yell {
+				t.Errorf("got %t, want %t", actual, tt.yell)
+			}
+		})
+	}
+}